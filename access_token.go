@@ -0,0 +1,22 @@
+package vk
+
+import "time"
+
+// AccessToken is the result of a VK OAuth token request, either the
+// client-credentials (service token) flow or the authorization-code flow.
+type AccessToken struct {
+	Token     string `json:"access_token"`
+	ExpiresIn int    `json:"expires_in,omitempty"` // seconds, 0 means it does not expire
+	UserID    int    `json:"user_id,omitempty"`
+
+	expiresAt time.Time
+}
+
+// Valid reports whether the token has a value and, if it carries an
+// expiry, whether that expiry is still in the future.
+func (t AccessToken) Valid() bool {
+	if t.Token == "" {
+		return false
+	}
+	return t.expiresAt.IsZero() || time.Now().Before(t.expiresAt)
+}
@@ -0,0 +1,185 @@
+package vk
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+
+	"github.com/pkg/errors"
+	"github.com/strongo/log"
+)
+
+const (
+	defaultBaseURL    = "https://api.vk.com/method"
+	defaultAPIVersion = "5.92"
+)
+
+// Doer is satisfied by *http.Client. Accepting it instead of a concrete
+// client lets callers plug in instrumented transports, alternative HTTP
+// clients, or test doubles.
+type Doer interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
+// apiResponse is implemented by every VK response envelope (Response,
+// searchResponse, ...) so api.do can check for an API-level error without
+// knowing the shape of the payload.
+type apiResponse interface {
+	apiError() *vkError
+}
+
+// API is a client for a single VK access token.
+type API struct {
+	appID       string
+	accessToken string
+	baseURL     string
+	apiVersion  string
+	userAgent   string
+	doer        Doer
+	limiter     *RateLimiter
+	RetryPolicy RetryPolicy
+}
+
+// APIOption configures an API returned by NewAPI.
+type APIOption func(*API)
+
+// WithHTTPClient sets the *http.Client used to issue requests.
+func WithHTTPClient(client *http.Client) APIOption {
+	return func(api *API) { api.doer = client }
+}
+
+// WithDoer sets an arbitrary Doer (e.g. an instrumented transport or a test
+// double) used to issue requests. WithDoer and WithHTTPClient set the same
+// field, so whichever of them is given last takes effect.
+func WithDoer(doer Doer) APIOption {
+	return func(api *API) { api.doer = doer }
+}
+
+// WithUserAgent sets the User-Agent header sent with every request.
+func WithUserAgent(userAgent string) APIOption {
+	return func(api *API) { api.userAgent = userAgent }
+}
+
+// WithBaseURL overrides the VK API base URL, mainly useful to point at a
+// mock server in tests.
+func WithBaseURL(baseURL string) APIOption {
+	return func(api *API) { api.baseURL = baseURL }
+}
+
+// WithAPIVersion overrides the VK API version sent as the `v` parameter.
+func WithAPIVersion(apiVersion string) APIOption {
+	return func(api *API) { api.apiVersion = apiVersion }
+}
+
+// WithAppID sets the VK app id the client acts on behalf of.
+func WithAppID(appID string) APIOption {
+	return func(api *API) { api.appID = appID }
+}
+
+// WithAccessToken sets the access token sent with every request.
+func WithAccessToken(accessToken string) APIOption {
+	return func(api *API) { api.accessToken = accessToken }
+}
+
+// WithRetryPolicy overrides the default retry/backoff policy used when a
+// request comes back with a retryable VK error.
+func WithRetryPolicy(policy RetryPolicy) APIOption {
+	return func(api *API) { api.RetryPolicy = policy }
+}
+
+// NewAPI builds an API from the given options. With no WithDoer/
+// WithHTTPClient option, it defaults to http.DefaultClient, and with no
+// WithBaseURL/WithAPIVersion it defaults to VK's production endpoint and
+// the version this package was written against.
+func NewAPI(opts ...APIOption) *API {
+	api := &API{limiter: NewRateLimiter(vkRequestsPerSecond)}
+	for _, opt := range opts {
+		opt(api)
+	}
+	if api.doer == nil {
+		api.doer = http.DefaultClient
+	}
+	return api
+}
+
+// url builds the VK API URL for method with params, adding the
+// access_token and v parameters.
+func (api *API) url(method string, params url.Values) string {
+	baseURL := api.baseURL
+	if baseURL == "" {
+		baseURL = defaultBaseURL
+	}
+	query := url.Values{}
+	for k, v := range params {
+		query[k] = v
+	}
+	if api.accessToken != "" {
+		query.Set("access_token", api.accessToken)
+	}
+	apiVersion := api.apiVersion
+	if apiVersion == "" {
+		apiVersion = defaultAPIVersion
+	}
+	query.Set("v", apiVersion)
+	return baseURL + "/" + method + "?" + query.Encode()
+}
+
+// do issues a VK API call for method with params, decoding the JSON
+// response into out. It retries on a retryable VkError per api.RetryPolicy,
+// is gated by api.limiter, and honors ctx cancellation both while waiting
+// for the limiter and for the in-flight HTTP request.
+func (api *API) do(ctx context.Context, method string, params url.Values, out apiResponse) error {
+	endpoint := api.url(method, params)
+	return withRetry(ctx, api.RetryPolicy, func() error {
+		if api.limiter != nil {
+			if err := api.limiter.Wait(ctx); err != nil {
+				return err
+			}
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return err
+		}
+		if api.userAgent != "" {
+			req.Header.Set("User-Agent", api.userAgent)
+		}
+
+		doer := api.doer
+		if doer == nil {
+			doer = http.DefaultClient
+		}
+
+		log.Debugf(ctx, "url: %v", endpoint)
+		resp, err := doer.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		log.Debugf(ctx, "VK response(status=%v) body: %v", resp.StatusCode, string(body))
+
+		// out is reused across retry attempts, and json.Unmarshal only
+		// overwrites fields present in the payload, so a stale error (or
+		// other field) from an earlier failed attempt would otherwise
+		// leak into the result of a later, successful one.
+		outVal := reflect.ValueOf(out).Elem()
+		outVal.Set(reflect.Zero(outVal.Type()))
+
+		if err = json.Unmarshal(body, out); err != nil {
+			return errors.Wrap(err, "Failed to unmarshal VK response")
+		}
+		if vkErr := out.apiError(); vkErr != nil {
+			log.Debugf(ctx, "VK API returned error - pass it upstream: %v", vkErr)
+			return vkErr
+		}
+		return nil
+	})
+}
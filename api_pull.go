@@ -1,31 +1,178 @@
 package vk
 
-import "sync"
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
 
+	"github.com/pkg/errors"
+)
+
+// VkAppsPull is a token cache shared by several VK apps, so a process
+// serving more than one app can look up a fresh token by app id instead of
+// every caller managing its own authentication.
 type VkAppsPull struct {
-	locker  sync.Locker
-	callbackUrl string
-	secrets map[string]string
-	tokens  map[string]AccessToken
+	mu          sync.RWMutex
+	callbackURL string
+	secrets     map[string]string
+	tokens      map[string]AccessToken
 }
 
-var vkAppsPull VkAppsPull = VkAppsPull{}
+var vkAppsPull = &VkAppsPull{}
 
+// RegisterVkApps initializes the shared token pool with the secrets for
+// appSecrets (keyed by VK app id) and the callback URL used to build OAuth
+// authorization redirects. It must be called once before GetToken or
+// ExchangeCode.
+func RegisterVkApps(callbackURL string, appSecrets map[string]string) {
+	vkAppsPull.mu.Lock()
+	defer vkAppsPull.mu.Unlock()
+	vkAppsPull.callbackURL = callbackURL
+	vkAppsPull.secrets = make(map[string]string, len(appSecrets))
+	for appID, secret := range appSecrets {
+		vkAppsPull.secrets[appID] = secret
+	}
+	vkAppsPull.tokens = make(map[string]AccessToken, len(appSecrets))
+}
 
-func RegisterVkApps(callbackUrl string, appSecrets map[string]string) {
+func (pull *VkAppsPull) addToken(appID string, token AccessToken) {
+	pull.mu.Lock()
+	pull.tokens[appID] = token
+	pull.mu.Unlock()
+}
 
+func (pull *VkAppsPull) cachedToken(appID string) (AccessToken, bool) {
+	pull.mu.RLock()
+	token, ok := pull.tokens[appID]
+	pull.mu.RUnlock()
+	return token, ok && token.Valid()
 }
 
-func (pull VkAppsPull) addToken(appID string, token AccessToken) {
-	pull.locker.Lock()
-	pull.tokens[appID] = token
-	pull.locker.Unlock()
+func (pull *VkAppsPull) secret(appID string) (string, bool) {
+	pull.mu.RLock()
+	secret, ok := pull.secrets[appID]
+	pull.mu.RUnlock()
+	return secret, ok
 }
 
-//func (pull VkAppsPull) GetToken(appID, secret string) AccessToken {
-//	if token, ok := pull.tokens[appID]; ok {
-//		return token
-//	}
-//	api := NewAPI(appID, secret, nil, "")
-//	api.Authenticate()
-//}
\ No newline at end of file
+// GetToken returns a cached access token for appID if it is still valid,
+// otherwise it performs VK's client-credentials (service token) flow
+// against https://oauth.vk.com/access_token and caches the result using the
+// expiry VK returns.
+func (pull *VkAppsPull) GetToken(c context.Context, appID string) (AccessToken, error) {
+	if token, ok := pull.cachedToken(appID); ok {
+		return token, nil
+	}
+
+	secret, known := pull.secret(appID)
+	if !known {
+		return AccessToken{}, errors.Errorf("unknown VK app id: %v", appID)
+	}
+
+	query := url.Values{}
+	query.Set("client_id", appID)
+	query.Set("client_secret", secret)
+	query.Set("grant_type", "client_credentials")
+
+	token, err := requestAccessToken(c, query)
+	if err != nil {
+		return AccessToken{}, err
+	}
+	pull.addToken(appID, token)
+	return token, nil
+}
+
+// ExchangeCode completes VK's OAuth authorization-code flow for appID,
+// using the callback URL supplied to RegisterVkApps as the redirect_uri,
+// and caches the resulting token.
+func (pull *VkAppsPull) ExchangeCode(c context.Context, appID, code string) (AccessToken, error) {
+	secret, known := pull.secret(appID)
+	if !known {
+		return AccessToken{}, errors.Errorf("unknown VK app id: %v", appID)
+	}
+	pull.mu.RLock()
+	callbackURL := pull.callbackURL
+	pull.mu.RUnlock()
+
+	query := url.Values{}
+	query.Set("client_id", appID)
+	query.Set("client_secret", secret)
+	query.Set("code", code)
+	query.Set("redirect_uri", callbackURL)
+
+	token, err := requestAccessToken(c, query)
+	if err != nil {
+		return AccessToken{}, err
+	}
+	pull.addToken(appID, token)
+	return token, nil
+}
+
+// Invalidate evicts any cached token for appID, forcing the next GetToken
+// call to re-authenticate.
+func (pull *VkAppsPull) Invalidate(appID string) {
+	pull.mu.Lock()
+	delete(pull.tokens, appID)
+	pull.mu.Unlock()
+}
+
+func requestAccessToken(c context.Context, query url.Values) (AccessToken, error) {
+	endpoint := "https://oauth.vk.com/access_token?" + query.Encode()
+	req, err := http.NewRequestWithContext(c, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return AccessToken{}, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return AccessToken{}, err
+	}
+	defer resp.Body.Close()
+
+	var token AccessToken
+	if err = json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return AccessToken{}, errors.Wrap(err, "failed to unmarshal VK OAuth response")
+	}
+	if token.Token == "" {
+		return AccessToken{}, errors.New("VK OAuth response did not include an access_token")
+	}
+	if token.ExpiresIn > 0 {
+		token.expiresAt = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+	return token, nil
+}
+
+// poolTransport fetches a fresh token from a VkAppsPull before every
+// request and sets it as the access_token query parameter, so an *API
+// built by NewAPIFromPool never has to be re-created when a token expires.
+type poolTransport struct {
+	pool  *VkAppsPull
+	appID string
+	base  http.RoundTripper
+}
+
+func (t *poolTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.pool.GetToken(req.Context(), t.appID)
+	if err != nil {
+		return nil, err
+	}
+	query := req.URL.Query()
+	query.Set("access_token", token.Token)
+	req.URL.RawQuery = query.Encode()
+
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(req)
+}
+
+// NewAPIFromPool returns an *API for appID that transparently pulls a
+// fresh access token from pool for every request, refreshing it as needed
+// instead of baking a single token in at construction time.
+func NewAPIFromPool(pool *VkAppsPull, appID string) *API {
+	httpClient := &http.Client{Transport: &poolTransport{pool: pool, appID: appID}}
+	return NewAPI(WithAppID(appID), WithHTTPClient(httpClient))
+}
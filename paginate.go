@@ -0,0 +1,88 @@
+package vk
+
+import (
+	"context"
+	"time"
+)
+
+// maxPageSize is the page size VK enforces on its listing endpoints
+// (users.search, friends.get, groups.getMembers, wall.get, ...).
+const maxPageSize = 1000
+
+// maxConsecutiveErrors bounds how many times in a row Paginate will call a
+// failing fetch before giving up, so a persistently failing fetch can't
+// spin the pagination goroutine forever.
+const maxConsecutiveErrors = 5
+
+// errorBackoff is the delay between retries of a failing fetch, scaled by
+// the number of consecutive failures seen so far.
+const errorBackoff = 200 * time.Millisecond
+
+// Pager configures a Paginate run.
+type Pager struct {
+	Count    int // page size requested from fetch, defaults to maxPageSize
+	Offset   int // starting offset
+	MaxItems int // stop once this many items have been fetched, 0 means unlimited
+}
+
+// PageResult is pushed onto the channel returned by Paginate for every page
+// fetched, including failed ones.
+type PageResult struct {
+	Items  []interface{}
+	Offset int
+	Err    error
+}
+
+// Paginate repeatedly calls fetch with an advancing offset/count, pushing a
+// PageResult for every page onto the returned channel. It stops and closes
+// the channel once fetch reports its results are exhausted (total reached,
+// or an empty page), pager.MaxItems is hit, ctx is cancelled, or fetch has
+// failed maxConsecutiveErrors times in a row. A failed page is still sent
+// on the channel without stopping pagination immediately, so callers can
+// decide for themselves whether to keep going; the offset is only advanced
+// on a successful page, so the next attempt re-fetches the same page
+// instead of skipping it, backing off for errorBackoff * consecutive
+// failures instead of spinning.
+func (api *API) Paginate(ctx context.Context, pager Pager, fetch func(offset, count int) (items []interface{}, total int, err error)) <-chan PageResult {
+	count := pager.Count
+	if count <= 0 {
+		count = maxPageSize
+	}
+	results := make(chan PageResult)
+	go func() {
+		defer close(results)
+		offset := pager.Offset
+		fetched := 0
+		consecutiveErrors := 0
+		for {
+			items, total, err := fetch(offset, count)
+			select {
+			case results <- PageResult{Items: items, Offset: offset, Err: err}:
+			case <-ctx.Done():
+				return
+			}
+			if err != nil {
+				consecutiveErrors++
+				if consecutiveErrors >= maxConsecutiveErrors {
+					return
+				}
+				select {
+				case <-time.After(errorBackoff * time.Duration(consecutiveErrors)):
+				case <-ctx.Done():
+					return
+				}
+				continue
+			}
+			consecutiveErrors = 0
+			fetched += len(items)
+			if len(items) == 0 || (total > 0 && fetched >= total) {
+				return
+			}
+			if pager.MaxItems > 0 && fetched >= pager.MaxItems {
+				return
+			}
+			offset += count
+		}
+	}()
+	return results
+}
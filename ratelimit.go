@@ -0,0 +1,53 @@
+package vk
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// vkRequestsPerSecond is VK's documented cap of 3 requests/sec per app
+// (http://vk.com/dev/api_requests).
+const vkRequestsPerSecond = 3
+
+// RateLimiter throttles outbound calls to VK's documented per-second cap so
+// callers don't have to serialize requests themselves.
+type RateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// NewRateLimiter returns a RateLimiter allowing requestsPerSecond calls to
+// go through per second. A non-positive requestsPerSecond falls back to
+// VK's documented default of 3 req/sec.
+func NewRateLimiter(requestsPerSecond int) *RateLimiter {
+	if requestsPerSecond <= 0 {
+		requestsPerSecond = vkRequestsPerSecond
+	}
+	return &RateLimiter{interval: time.Second / time.Duration(requestsPerSecond)}
+}
+
+// Wait blocks until the next request is allowed to proceed, or ctx is done.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	wait := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
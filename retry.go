@@ -0,0 +1,108 @@
+package vk
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// VK error codes that mean "back off and try again"
+// (http://vk.com/dev/errors).
+const (
+	ErrCodeTooManyRequestsPerSecond = 6
+	ErrCodeFloodControl             = 9
+)
+
+// vkRetryableError is a VkError for a known-retryable VK response, used to
+// build the ErrTooManyRequestsPerSecond and ErrFloodControl sentinels.
+type vkRetryableError struct {
+	code    int
+	message string
+}
+
+func (err vkRetryableError) Error() string    { return err.message }
+func (err vkRetryableError) VkErrorCode() int { return err.code }
+
+// Is lets errors.Is match a vkRetryableError sentinel against any VkError
+// (such as the *vkError returned by api.do) by comparing error codes.
+func (err vkRetryableError) Is(target error) bool {
+	t, ok := target.(VkError)
+	if !ok {
+		return false
+	}
+	return err.code == t.VkErrorCode()
+}
+
+var (
+	// ErrTooManyRequestsPerSecond is VK error code 6. Compare against it
+	// with errors.Is, e.g. errors.Is(err, vk.ErrTooManyRequestsPerSecond).
+	ErrTooManyRequestsPerSecond error = vkRetryableError{ErrCodeTooManyRequestsPerSecond, "Too many requests per second"}
+	// ErrFloodControl is VK error code 9. Compare against it with
+	// errors.Is, e.g. errors.Is(err, vk.ErrFloodControl).
+	ErrFloodControl error = vkRetryableError{ErrCodeFloodControl, "Flood control"}
+)
+
+// RetryPolicy configures how *API retries requests that fail with a
+// retryable VK error (ErrTooManyRequestsPerSecond, ErrFloodControl).
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	Jitter      bool
+}
+
+// DefaultRetryPolicy is used by *API when RetryPolicy is the zero value.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	BaseDelay:   200 * time.Millisecond,
+	MaxDelay:    5 * time.Second,
+	Jitter:      true,
+}
+
+func isRetryableVkError(err error) bool {
+	return errors.Is(err, ErrTooManyRequestsPerSecond) || errors.Is(err, ErrFloodControl)
+}
+
+// delay returns the backoff to wait before the given zero-based attempt,
+// i.e. BaseDelay * 2^attempt capped at MaxDelay and optionally jittered.
+func (policy RetryPolicy) delay(attempt int) time.Duration {
+	d := policy.BaseDelay
+	for i := 0; i < attempt; i++ {
+		if policy.MaxDelay > 0 && d >= policy.MaxDelay {
+			break
+		}
+		d *= 2
+	}
+	if policy.MaxDelay > 0 && d > policy.MaxDelay {
+		d = policy.MaxDelay
+	}
+	if policy.Jitter && d > 0 {
+		d = d/2 + time.Duration(rand.Int63n(int64(d)/2+1))
+	}
+	return d
+}
+
+// withRetry calls fn, retrying on retryable VK errors per policy while
+// honoring ctx.Done() between attempts. policy defaults to
+// DefaultRetryPolicy when its MaxAttempts is unset.
+func withRetry(ctx context.Context, policy RetryPolicy, fn func() error) error {
+	if policy.MaxAttempts == 0 {
+		policy = DefaultRetryPolicy
+	}
+	var err error
+	for attempt := 0; attempt < policy.MaxAttempts; attempt++ {
+		if err = fn(); err == nil || !isRetryableVkError(err) {
+			return err
+		}
+		if attempt == policy.MaxAttempts-1 {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(policy.delay(attempt)):
+		}
+	}
+	return err
+}
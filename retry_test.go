@@ -0,0 +1,77 @@
+package vk
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func jsonResponse(body string) *http.Response {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       ioutil.NopCloser(strings.NewReader(body)),
+		Header:     make(http.Header),
+	}
+}
+
+func TestUsersGetRetriesOnTooManyRequestsPerSecond(t *testing.T) {
+	var calls int
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		if calls == 1 {
+			return jsonResponse(`{"error":{"error_code":6,"error_msg":"Too many requests per second"}}`), nil
+		}
+		return jsonResponse(`{"response":[{"id":1,"first_name":"A","last_name":"B"}]}`), nil
+	})
+
+	api := NewAPI(
+		WithDoer(&http.Client{Transport: transport}),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}),
+	)
+	api.limiter = nil // rate limiting isn't under test here
+
+	users, err := api.UsersGet(context.Background(), []string{"1"}, nil, "nom")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(users) != 1 || users[0].ID != 1 {
+		t.Fatalf("unexpected users: %+v", users)
+	}
+	if calls != 2 {
+		t.Fatalf("expected 2 calls (1 retry), got %v", calls)
+	}
+}
+
+func TestUsersGetStopsRetryingWhenContextCancelled(t *testing.T) {
+	var calls int
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return jsonResponse(`{"error":{"error_code":9,"error_msg":"Flood control"}}`), nil
+	})
+
+	api := NewAPI(
+		WithDoer(&http.Client{Transport: transport}),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 100, BaseDelay: 50 * time.Millisecond, MaxDelay: time.Second}),
+	)
+	api.limiter = nil
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := api.UsersGet(ctx, []string{"1"}, nil, "nom")
+	if err == nil {
+		t.Fatal("expected an error from a cancelled context, got nil")
+	}
+	if calls == 0 || calls >= 100 {
+		t.Fatalf("expected retries to stop early once ctx was cancelled, got %v calls", calls)
+	}
+}
@@ -1,15 +1,14 @@
 package vk
 
 import (
-	"encoding/json"
-	"net/http"
-	"strings"
-	"strconv"
-	"fmt"
 	"context"
-	"io/ioutil"
-	"github.com/strongo/log"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
 	"github.com/pkg/errors"
+	"github.com/strongo/log"
 )
 
 var (
@@ -138,6 +137,21 @@ func (err vkError) VkErrorCode() int {
 	return err.Code
 }
 
+// Is lets errors.Is match a vkError against a VK error sentinel (such as
+// ErrTooManyRequestsPerSecond or ErrFloodControl) by comparing error codes,
+// without either side needing to share a concrete type.
+func (err vkError) Is(target error) bool {
+	t, ok := target.(VkError)
+	if !ok {
+		return false
+	}
+	return err.Code == t.VkErrorCode()
+}
+
+func (r *Response) apiError() *vkError {
+	return r.Error
+}
+
 func (api *API) GetUserByIntID(c context.Context, userID int64, nameCase string, fields ...string) (UserInfo, error) {
 	if users, err := api.UsersGet(c, []string{strconv.FormatInt(userID, 10)}, fields, nameCase); err != nil {
 		return UserInfo{}, err
@@ -168,47 +182,100 @@ func (api *API) UsersGet(c context.Context, userIds []string, fields []string, n
 		return nil, errors.New("the only available name cases are: " + strings.Join(NameCases, ", "))
 	}
 
-	endpoint := api.getAPIURL("users.get")
-	query := endpoint.Query()
-	query.Set("user_ids", strings.Join(userIds, ","))
+	if len(userIds) <= maxPageSize {
+		return api.usersGet(c, userIds, fields, nameCase)
+	}
+
+	var users []UserInfo
+	for offset := 0; offset < len(userIds); offset += maxPageSize {
+		end := offset + maxPageSize
+		if end > len(userIds) {
+			end = len(userIds)
+		}
+		batch, err := api.usersGet(c, userIds[offset:end], fields, nameCase)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, batch...)
+	}
+	return users, nil
+}
+
+// usersGet calls users.get for a single batch of no more than
+// maxPageSize ids.
+func (api *API) usersGet(c context.Context, userIds []string, fields []string, nameCase string) ([]UserInfo, error) {
+	params := url.Values{}
+	params.Set("user_ids", strings.Join(userIds, ","))
 
 	if len(fields) > 0 {
 		fieldsStr := strings.Join(fields, ",")
 		log.Debugf(c, "VK fields: "+fieldsStr)
-		query.Set("fields", fieldsStr)
+		params.Set("fields", fieldsStr)
 	}
 	if nameCase != "" {
-		query.Set("name_case", nameCase)
+		params.Set("name_case", nameCase)
 	}
 
-	endpoint.RawQuery = query.Encode()
-
-	var err error
-	var resp *http.Response
 	var response Response
-
-	httpClient := api.httpClient
-	if httpClient == nil {
-		httpClient = http.DefaultClient
-	}
-	url := endpoint.String()
-	log.Debugf(c, "url: %v", url)
-	if resp, err = httpClient.Get(url); err != nil {
+	if err := api.do(c, "users.get", params, &response); err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	responseBody, err := ioutil.ReadAll(resp.Body)
+	return response.Response, nil
+}
 
-	log.Debugf(c, "VK response(status=%v) body: %v", resp.StatusCode, string(responseBody))
+// UserResult is pushed onto the channel returned by UsersGetStream, one per
+// user VK returns, plus one with Err set for every failed batch request.
+type UserResult struct {
+	User UserInfo
+	Err  error
+}
 
-	if err = json.Unmarshal(responseBody, &response); err != nil {
-		return nil, errors.Wrap(err, "Failed to unmarshal VK response")
-	}
-	log.Debugf(c, "Unmarshalled VK response: %v", response)
-	if response.Error != nil {
-		err = response.Error
-		log.Debugf(c, "VK API returned error - pass it upstream: %v", err)
-	}
-	return response.Response, err
+// UsersGetStream streams the result of UsersGet over a channel. It is built
+// on top of Paginate, fetching ids in batches of maxPageSize so a caller
+// passing a large slice of ids doesn't have to hold every UserInfo in
+// memory at once or write its own batching/retry loop.
+func (api *API) UsersGetStream(c context.Context, userIds []string, fields []string, nameCase string) <-chan UserResult {
+	results := make(chan UserResult)
+	go func() {
+		defer close(results)
+		if len(userIds) == 0 {
+			return
+		}
+		fetch := func(offset, count int) ([]interface{}, int, error) {
+			if offset >= len(userIds) {
+				return nil, len(userIds), nil
+			}
+			end := offset + count
+			if end > len(userIds) {
+				end = len(userIds)
+			}
+			users, err := api.usersGet(c, userIds[offset:end], fields, nameCase)
+			if err != nil {
+				return nil, 0, err
+			}
+			items := make([]interface{}, len(users))
+			for i, u := range users {
+				items[i] = u
+			}
+			return items, len(userIds), nil
+		}
+		for result := range api.Paginate(c, Pager{Count: maxPageSize}, fetch) {
+			if result.Err != nil {
+				select {
+				case results <- UserResult{Err: result.Err}:
+				case <-c.Done():
+					return
+				}
+				continue
+			}
+			for _, item := range result.Items {
+				select {
+				case results <- UserResult{User: item.(UserInfo)}:
+				case <-c.Done():
+					return
+				}
+			}
+		}
+	}()
+	return results
 }
@@ -0,0 +1,238 @@
+package vk
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	// RelationNotMarried and the rest of the RelationXxx constants are bit
+	// flags describing VK's `relation` field. SearchCriteria.RelationStatus
+	// is a bitmask so callers can search for several statuses at once.
+	RelationNotMarried = 1 << iota
+	RelationInRelationship
+	RelationEngaged
+	RelationMarried
+	RelationComplicated
+	RelationActivelySearching
+	RelationInLove
+	RelationCivilMarriage
+)
+
+// relationStatusValues maps a single RelationXxx bit to the `status` value
+// expected by VK's users.search (http://vk.com/dev/objects/relation).
+var relationStatusValues = map[int]int{
+	RelationNotMarried:        1,
+	RelationInRelationship:    2,
+	RelationEngaged:           3,
+	RelationMarried:           4,
+	RelationComplicated:       5,
+	RelationActivelySearching: 6,
+	RelationInLove:            7,
+	RelationCivilMarriage:     8,
+}
+
+// SearchCriteria describes the filters accepted by users.search.
+type SearchCriteria struct {
+	Query          string
+	City           int
+	Country        int
+	Sex            int // 0=any, 1=female, 2=male
+	MinAge         int
+	MaxAge         int
+	HomeTown       string
+	Online         bool
+	HasPhoto       bool
+	RelationStatus int // bitmask of RelationXxx constants, 0 means any
+	Offset         int
+	Count          int
+	Fields         []string
+	NameCase       string
+}
+
+// UserSearchPage is emitted by UsersSearchAll for every page fetched.
+type UserSearchPage struct {
+	Users  []UserInfo
+	Offset int
+	Count  int
+	Err    error
+}
+
+// searchResponse mirrors the shape of users.search, which wraps its items
+// in a `{count, items}` object rather than returning a bare array like
+// users.get does.
+type searchResponse struct {
+	Error    *vkError `json:"error"`
+	Response struct {
+		Count int        `json:"count"`
+		Items []UserInfo `json:"items"`
+	} `json:"response"`
+}
+
+func (r *searchResponse) apiError() *vkError {
+	return r.Error
+}
+
+func (criteria SearchCriteria) params() url.Values {
+	q := url.Values{}
+	if criteria.Query != "" {
+		q.Set("q", criteria.Query)
+	}
+	if criteria.City != 0 {
+		q.Set("city", strconv.Itoa(criteria.City))
+	}
+	if criteria.Country != 0 {
+		q.Set("country", strconv.Itoa(criteria.Country))
+	}
+	if criteria.Sex != 0 {
+		q.Set("sex", strconv.Itoa(criteria.Sex))
+	}
+	if criteria.MinAge != 0 {
+		q.Set("age_from", strconv.Itoa(criteria.MinAge))
+	}
+	if criteria.MaxAge != 0 {
+		q.Set("age_to", strconv.Itoa(criteria.MaxAge))
+	}
+	if criteria.HomeTown != "" {
+		q.Set("hometown", criteria.HomeTown)
+	}
+	if criteria.Online {
+		q.Set("online", "1")
+	}
+	if criteria.HasPhoto {
+		q.Set("has_photo", "1")
+	}
+	if len(criteria.Fields) > 0 {
+		q.Set("fields", strings.Join(criteria.Fields, ","))
+	}
+	if criteria.NameCase != "" {
+		q.Set("name_case", criteria.NameCase)
+	}
+	q.Set("offset", strconv.Itoa(criteria.Offset))
+	if criteria.Count > 0 {
+		q.Set("count", strconv.Itoa(criteria.Count))
+	}
+	return q
+}
+
+// relationStatuses returns the VK `status` values set in the bitmask, sorted
+// for deterministic request order. A zero mask means "any status".
+func relationStatuses(mask int) []int {
+	if mask == 0 {
+		return nil
+	}
+	statuses := make([]int, 0, len(relationStatusValues))
+	for bit := 1; bit <= RelationCivilMarriage; bit <<= 1 {
+		if mask&bit != 0 {
+			statuses = append(statuses, relationStatusValues[bit])
+		}
+	}
+	return statuses
+}
+
+// UsersSearch implements method http://vk.com/dev/users.search
+//
+// VK only accepts a single `status` value per request, so when
+// criteria.RelationStatus selects more than one status UsersSearch issues
+// one request per status and merges the results, deduplicating by user ID.
+func (api *API) UsersSearch(c context.Context, criteria SearchCriteria) ([]UserInfo, error) {
+	statuses := relationStatuses(criteria.RelationStatus)
+	if len(statuses) <= 1 {
+		users, _, err := api.usersSearch(c, criteria, statuses)
+		return users, err
+	}
+
+	seen := make(map[int]bool, len(statuses))
+	var users []UserInfo
+	for _, status := range statuses {
+		page, _, err := api.usersSearch(c, criteria, []int{status})
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range page {
+			if !seen[u.ID] {
+				seen[u.ID] = true
+				users = append(users, u)
+			}
+		}
+	}
+	return users, nil
+}
+
+// usersSearch issues a single users.search request and also returns VK's
+// reported total (response.count), which is only meaningful for a single
+// status (or no status filter at all) since VK has no notion of a combined
+// total across several per-status requests.
+func (api *API) usersSearch(c context.Context, criteria SearchCriteria, statuses []int) ([]UserInfo, int, error) {
+	params := criteria.params()
+	if len(statuses) == 1 {
+		params.Set("status", strconv.Itoa(statuses[0]))
+	}
+
+	var response searchResponse
+	if err := api.do(c, "users.search", params, &response); err != nil {
+		return nil, 0, err
+	}
+	return response.Response.Items, response.Response.Count, nil
+}
+
+// UsersSearchAll paginates through users.search, pushing one UserSearchPage
+// per batch. VK only accepts a single `status` value per request and only
+// reports a usable total (response.count) for a single status, so when
+// criteria.RelationStatus selects more than one status, each status is
+// paginated independently - on its own offset, via its own Paginate run -
+// rather than sharing criteria.Offset across all statuses the way
+// UsersSearch does for a one-shot call.
+func (api *API) UsersSearchAll(c context.Context, criteria SearchCriteria) <-chan UserSearchPage {
+	if criteria.Count <= 0 {
+		criteria.Count = 100
+	}
+	statuses := relationStatuses(criteria.RelationStatus)
+	if len(statuses) == 0 {
+		statuses = []int{0} // 0 means "no status filter", see fetch below
+	}
+
+	pages := make(chan UserSearchPage)
+	go func() {
+		defer close(pages)
+		for _, status := range statuses {
+			status := status
+			fetch := func(offset, count int) ([]interface{}, int, error) {
+				pageCriteria := criteria
+				pageCriteria.Offset = offset
+				pageCriteria.Count = count
+				var reqStatuses []int
+				if status != 0 {
+					reqStatuses = []int{status}
+				}
+				users, total, err := api.usersSearch(c, pageCriteria, reqStatuses)
+				if err != nil {
+					return nil, 0, err
+				}
+				items := make([]interface{}, len(users))
+				for i, u := range users {
+					items[i] = u
+				}
+				return items, total, nil
+			}
+			for result := range api.Paginate(c, Pager{Count: criteria.Count, Offset: criteria.Offset}, fetch) {
+				page := UserSearchPage{Offset: result.Offset, Err: result.Err}
+				if result.Err == nil {
+					page.Users = make([]UserInfo, len(result.Items))
+					for i, item := range result.Items {
+						page.Users[i] = item.(UserInfo)
+					}
+					page.Count = len(page.Users)
+				}
+				select {
+				case pages <- page:
+				case <-c.Done():
+					return
+				}
+			}
+		}
+	}()
+	return pages
+}